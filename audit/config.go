@@ -0,0 +1,69 @@
+package audit
+
+import "errors"
+
+// Config mirrors the "audit" stanza of the goldfish config file, e.g.
+//
+//	audit {
+//	  type = "file"
+//	  path = "/var/log/goldfish-audit.log"
+//	}
+//
+// Only the fields relevant to the selected Type need to be set.
+type Config struct {
+	Type string `hcl:"type"`
+
+	// file
+	Path string `hcl:"path"`
+
+	// vault
+	Namespace string `hcl:"namespace"`
+	Mount     string `hcl:"mount"`
+
+	// syslog
+	Tag string `hcl:"tag"`
+}
+
+// ConfigureFromConfig builds a Sink from a Config and installs it. An
+// empty/zero Config disables auditing rather than erroring, since audit
+// configuration is optional.
+//
+// Callers are expected to parse the "audit" stanza out of the goldfish
+// config file into a Config and call this once at server startup, e.g.
+// `audit.ConfigureFromConfig(cfg.Audit)`.
+func ConfigureFromConfig(cfg Config) error {
+	switch cfg.Type {
+	case "":
+		Configure(nil)
+		return nil
+
+	case "file":
+		if cfg.Path == "" {
+			return errors.New("audit: file sink requires 'path'")
+		}
+		Configure(NewFileSink(cfg.Path))
+		return nil
+
+	case "vault":
+		if cfg.Mount == "" {
+			return errors.New("audit: vault sink requires 'mount'")
+		}
+		Configure(NewVaultSink(cfg.Namespace, cfg.Mount))
+		return nil
+
+	case "syslog":
+		tag := cfg.Tag
+		if tag == "" {
+			tag = "goldfish"
+		}
+		sink, err := NewSyslogSink(tag)
+		if err != nil {
+			return err
+		}
+		Configure(sink)
+		return nil
+
+	default:
+		return errors.New("audit: unknown sink type: " + cfg.Type)
+	}
+}
@@ -0,0 +1,30 @@
+package audit
+
+import "testing"
+
+func TestConfigureFromConfig(t *testing.T) {
+	defer Configure(nil)
+
+	cases := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"empty disables auditing", Config{}, false},
+		{"file requires path", Config{Type: "file"}, true},
+		{"file", Config{Type: "file", Path: "/tmp/goldfish-audit.log"}, false},
+		{"vault requires mount", Config{Type: "vault"}, true},
+		{"vault", Config{Type: "vault", Namespace: "team-a/", Mount: "goldfish-audit"}, false},
+		{"unknown type", Config{Type: "bogus"}, true},
+	}
+
+	for _, tc := range cases {
+		err := ConfigureFromConfig(tc.cfg)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected an error, got nil", tc.name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
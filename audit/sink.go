@@ -0,0 +1,88 @@
+// Package audit records who created, approved, or rejected a request, so
+// that history survives past the point where the cubbyhole entry backing
+// it is deleted.
+package audit
+
+import (
+	"log"
+	"time"
+)
+
+// Decision is the outcome an Event records.
+type Decision string
+
+const (
+	DecisionCreated  Decision = "created"
+	DecisionApproved Decision = "approved"
+	DecisionRejected Decision = "rejected"
+)
+
+// Event is a single audit record. Body should already be redacted by the
+// caller (see Redact) before it reaches a Sink.
+type Event struct {
+	Time        time.Time
+	Actor       string
+	Accessor    string
+	RequestType string
+	Hash        string
+	Decision    Decision
+	Body        map[string]interface{}
+}
+
+// Sink persists an Event somewhere durable.
+type Sink interface {
+	Write(Event) error
+}
+
+// active is the configured sink. A nil sink means auditing is disabled,
+// which is also what happens if configuration fails - auditing should
+// never be a prerequisite for a legitimate approval to go through.
+var active Sink
+
+// Configure sets the sink used by Record. Passing nil disables auditing.
+func Configure(sink Sink) {
+	active = sink
+}
+
+// Record writes an event to the configured sink, if any. Errors are
+// logged and swallowed: a sink outage must never block Add/Approve/Reject.
+func Record(e Event) {
+	if active == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := active.Write(e); err != nil {
+		log.Printf("[WARN] audit: failed to record %s event for request %s: %v", e.Decision, e.Hash, err)
+	}
+}
+
+// redactedKeys are fields that are either secrets themselves, or are
+// bulky enough (full policy rules) that only their presence, not their
+// content, belongs in an audit trail.
+var redactedKeys = map[string]string{
+	"Policy":        "<redacted>",
+	"PolicyContent": "<redacted>",
+	"Rules":         "<redacted>",
+	"Data":          "<redacted>",
+	"ClientToken":   "<omitted>",
+	"Token":         "<omitted>",
+	"UnsealToken":   "<omitted>",
+	"PGPKey":        "<omitted>",
+}
+
+// Redact returns a copy of a request body safe to hand to a Sink: secret
+// material is omitted, and bulky policy/data fields are replaced with a
+// placeholder rather than copied verbatim.
+func Redact(raw map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		if placeholder, ok := redactedKeys[k]; ok {
+			redacted[k] = placeholder
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
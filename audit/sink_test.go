@@ -0,0 +1,24 @@
+package audit
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	raw := map[string]interface{}{
+		"Type":        "policy",
+		"Policy":      "path \"secret/*\" { capabilities = [\"read\"] }",
+		"ClientToken": "s.abc123",
+		"RequestedBy": "alice",
+	}
+
+	redacted := Redact(raw)
+
+	if redacted["Type"] != "policy" || redacted["RequestedBy"] != "alice" {
+		t.Fatalf("non-sensitive fields should pass through unchanged, got %+v", redacted)
+	}
+	if redacted["Policy"] == raw["Policy"] {
+		t.Fatalf("Policy should be redacted, not copied verbatim")
+	}
+	if redacted["ClientToken"] == raw["ClientToken"] {
+		t.Fatalf("ClientToken should be omitted, not copied verbatim")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogSink forwards events to the local syslog daemon. Not available
+// on windows, where log/syslog doesn't exist; see syslog_sink_windows.go.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{writer: writer}, nil
+}
+
+func (s *SyslogSink) Write(e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(b))
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package audit
+
+import "errors"
+
+// SyslogSink is unavailable on windows; log/syslog doesn't exist there.
+type SyslogSink struct{}
+
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog audit sink is not supported on windows")
+}
+
+func (s *SyslogSink) Write(e Event) error {
+	return errors.New("syslog audit sink is not supported on windows")
+}
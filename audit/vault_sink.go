@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// VaultSink writes every event as a new version of a key in a KV v2
+// mount, so the full approval history for a request is preserved even
+// after the request itself has been approved, rejected, or reaped.
+type VaultSink struct {
+	Namespace string
+	Mount     string
+}
+
+func NewVaultSink(namespace, mount string) *VaultSink {
+	return &VaultSink{Namespace: namespace, Mount: mount}
+}
+
+func (s *VaultSink) Write(e Event) error {
+	path := fmt.Sprintf("%s/%s", s.Mount, e.Hash)
+	return vault.WriteKVv2(s.Namespace, path, map[string]interface{}{
+		"time":         e.Time.Format("2006-01-02T15:04:05Z07:00"),
+		"actor":        e.Actor,
+		"accessor":     e.Accessor,
+		"request_type": e.RequestType,
+		"hash":         e.Hash,
+		"decision":     string(e.Decision),
+		"body":         e.Body,
+	})
+}
@@ -0,0 +1,68 @@
+package request
+
+import (
+	"regexp"
+	"strings"
+)
+
+// vaultStatusCodeRe pulls the HTTP status code out of the error strings
+// produced by github.com/hashicorp/vault/api, e.g.
+// "...\nCode: 500. Errors:\n\n* internal error". Note the period after
+// the code, not a colon.
+var vaultStatusCodeRe = regexp.MustCompile(`Code: (\d+)\.`)
+
+// RequestError wraps an error encountered while driving Vault through an
+// approval (wrap/unwrap/generate-root) and records whether it's worth
+// retrying. This mirrors how Nomad classifies Vault unwrap failures:
+// network blips, 5xxs, and permission errors against an otherwise valid
+// token are worth retrying; a wrapping token that Vault has already
+// forgotten about is not, and callers should reset progress instead of
+// retrying forever.
+type RequestError struct {
+	err         error
+	recoverable bool
+}
+
+func NewRequestError(err error, recoverable bool) *RequestError {
+	return &RequestError{err: err, recoverable: recoverable}
+}
+
+func (e *RequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RequestError) Recoverable() bool {
+	return e.recoverable
+}
+
+// classifyVaultError inspects an error returned by WrapData, UnwrapData or
+// the sys/generate-root endpoints and decides whether it's safe to retry.
+func classifyVaultError(err error) *RequestError {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "wrapping token is not valid or does not exist"):
+		return NewRequestError(err, false)
+	case strings.Contains(msg, "permission denied"):
+		return NewRequestError(err, true)
+	case isServerError(msg):
+		return NewRequestError(err, true)
+	case strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "timeout"),
+		strings.Contains(msg, "EOF"):
+		return NewRequestError(err, true)
+	default:
+		// an error we don't recognize is treated as unrecoverable, so a
+		// broken bundle doesn't get silently retried forever
+		return NewRequestError(err, false)
+	}
+}
+
+// isServerError reports whether msg carries a 5xx status code from the
+// vault api package's error formatting.
+func isServerError(msg string) bool {
+	m := vaultStatusCodeRe.FindStringSubmatch(msg)
+	return m != nil && strings.HasPrefix(m[1], "5")
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyVaultError(t *testing.T) {
+	cases := []struct {
+		name        string
+		err         error
+		recoverable bool
+	}{
+		{"invalid wrapping token", errors.New("Error making API request.\n\nURL: PUT https://vault:8200/v1/sys/wrapping/unwrap\nCode: 400. Errors:\n\n* wrapping token is not valid or does not exist"), false},
+		{"permission denied", errors.New("Error making API request.\n\nURL: PUT https://vault:8200/v1/sys/wrapping/unwrap\nCode: 403. Errors:\n\n* permission denied"), true},
+		{"server error", errors.New("Error making API request.\n\nURL: PUT https://vault:8200/v1/sys/wrapping/unwrap\nCode: 500. Errors:\n\n* internal error"), true},
+		{"connection refused", errors.New("dial tcp 127.0.0.1:8200: connection refused"), true},
+		{"unrecognized error", errors.New("something unexpected happened"), false},
+	}
+
+	for _, tc := range cases {
+		rerr := classifyVaultError(tc.err)
+		if rerr.Recoverable() != tc.recoverable {
+			t.Errorf("%s: expected Recoverable()=%v, got %v", tc.name, tc.recoverable, rerr.Recoverable())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package request
+
+import (
+	"errors"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+)
+
+const (
+	// how long a request sits in the cubbyhole before Reap considers it
+	// stale and removes it
+	defaultRequestTTL = 24 * time.Hour
+
+	// must match the duration passed to vault.WrapData in appendUnseal
+	wrappingTokenTTL = 60 * time.Minute
+
+	// appendUnseal refuses to extend a bundle once its oldest wrapping
+	// token is this close to expiring, rather than let unwrapUnseals
+	// discover the expiry later and reset progress anyway
+	nearExpiryWindow = 5 * time.Minute
+)
+
+// Reap deletes requests (and their unseal-wrapping-token bundles) whose
+// ExpiresAt has passed. It is meant to be run periodically from a
+// goroutine in the server's startup wiring, not on the request path.
+func Reap(auth vault.AuthInfo) error {
+	hashes, err := vault.ListCubbyhole(auth.Namespace, "requests")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, hash := range hashes {
+		resp, err := vault.ReadFromCubbyhole(auth.Namespace, "requests/"+hash)
+		if err != nil || resp == nil {
+			continue
+		}
+
+		expiresRaw, ok := resp.Data["ExpiresAt"].(string)
+		if !ok {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, expiresRaw)
+		if err != nil || now.Before(expiresAt) {
+			continue
+		}
+
+		vault.DeleteFromCubbyhole(auth.Namespace, "requests/"+hash)
+		vault.DeleteFromCubbyhole(auth.Namespace, "unseal_wrapping_tokens/"+hash)
+	}
+	return nil
+}
+
+// StartReaper runs Reap on a ticker until the returned stop function is
+// called. This is the goroutine the server's startup wiring should spawn
+// once, per namespace, at boot: e.g. `defer request.StartReaper(auth,
+// time.Hour)()`.
+func StartReaper(auth vault.AuthInfo, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				Reap(auth)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// newExpiry returns the CreatedAt/ExpiresAt pair, RFC3339-formatted, for
+// a request being created right now.
+func newExpiry() (createdAt, expiresAt string) {
+	now := time.Now().UTC()
+	return now.Format(time.RFC3339), now.Add(defaultRequestTTL).Format(time.RFC3339)
+}
+
+// errNearExpiry is returned by appendUnseal when a bundle's oldest
+// wrapping token is too close to its TTL to safely accept another share.
+var errNearExpiry = errors.New(
+	"progress reset due to near-expiry: this request's unseal bundle is " +
+		"within 5 minutes of its wrapping token TTL, please start over")
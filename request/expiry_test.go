@@ -0,0 +1,26 @@
+package request
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewExpiry(t *testing.T) {
+	createdAt, expiresAt := newExpiry()
+
+	created, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		t.Fatalf("CreatedAt is not RFC3339: %v", err)
+	}
+	expires, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		t.Fatalf("ExpiresAt is not RFC3339: %v", err)
+	}
+
+	if !expires.After(created) {
+		t.Fatalf("expected ExpiresAt (%s) to be after CreatedAt (%s)", expiresAt, createdAt)
+	}
+	if expires.Sub(created) != defaultRequestTTL {
+		t.Fatalf("expected a %s TTL, got %s", defaultRequestTTL, expires.Sub(created))
+	}
+}
@@ -0,0 +1,136 @@
+package request
+
+import (
+	"errors"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// MountRequest represents a proposed change to a secret or auth mount:
+// enabling a new one, tuning an existing one, or disabling one outright.
+// It is approved the same way a PolicyRequest is: approvers submit unseal
+// keys until a root token can be generated, and that root token is used
+// once to perform the mount operation against sys/mounts or sys/auth.
+type MountRequest struct {
+	Type string
+
+	// Vault Enterprise namespace this request was created in
+	Namespace string
+
+	// "secret" or "auth", selecting sys/mounts vs sys/auth
+	MountClass string
+
+	// "enable", "tune" or "disable"
+	Action string
+
+	// mount path, e.g. "secret/" or "aws/"
+	Path string
+
+	// mount type, e.g. "kv", "aws", "approle" (ignored for tune/disable)
+	MountType string
+
+	// tune/enable options, passed straight through to vault
+	Config map[string]interface{}
+
+	// optional: encrypt the generated root token to this PGP key
+	// (fingerprint or armored public key) instead of using an OTP
+	PGPKey string
+
+	RequestedBy string
+
+	// RFC3339 timestamps; ExpiresAt is enforced by request.Reap
+	CreatedAt string
+	ExpiresAt string
+}
+
+func (r *MountRequest) IsRootOnly() bool {
+	return true
+}
+
+// Verify checks that the request is still well formed. The actual
+// authorization decision (who is allowed to approve) is made by the
+// unseal key quorum required by Approve.
+func (r *MountRequest) Verify(auth vault.AuthInfo) error {
+	switch r.MountClass {
+	case "secret", "auth":
+	default:
+		return errors.New("Invalid mount class: " + r.MountClass)
+	}
+	switch r.Action {
+	case "enable", "tune", "disable":
+	default:
+		return errors.New("Invalid mount action: " + r.Action)
+	}
+	if r.Path == "" {
+		return errors.New("Mount path cannot be empty")
+	}
+	return nil
+}
+
+// Approve appends an unseal key share to the request's progress, and
+// once enough shares have been collected, generates a root token and
+// performs the mount operation on behalf of the approvers.
+func (r *MountRequest) Approve(hash, unseal string) error {
+	return completeApproval(r.Namespace, hash, unseal, r.PGPKey, r.RequestedBy, r.Type,
+		map[string]interface{}{
+			"Type":        r.Type,
+			"Namespace":   r.Namespace,
+			"MountClass":  r.MountClass,
+			"Action":      r.Action,
+			"Path":        r.Path,
+			"MountType":   r.MountType,
+			"Config":      r.Config,
+			"PGPKey":      r.PGPKey,
+			"RequestedBy": r.RequestedBy,
+			"CreatedAt":   r.CreatedAt,
+			"ExpiresAt":   r.ExpiresAt,
+		},
+		func(token string) error {
+			return vault.ApplyMountChange(token, r.MountClass, r.Action, r.Path, r.MountType, r.Config)
+		})
+}
+
+func (r *MountRequest) Reject(auth vault.AuthInfo, hash string) error {
+	return rejectRequest(r.Namespace, hash)
+}
+
+func (r *MountRequest) Create(auth vault.AuthInfo, raw map[string]interface{}) (string, error) {
+	if err := mapstructure.Decode(raw, r); err != nil {
+		return "", err
+	}
+	r.Type = "mount"
+	r.RequestedBy = auth.DisplayName
+	// Namespace is part of the request's security context, not
+	// client-suppliable data: it always comes from the requester's own
+	// authenticated auth info, never from raw, even if raw sets one.
+	r.Namespace = auth.Namespace
+	r.CreatedAt, r.ExpiresAt = newExpiry()
+
+	if err := r.Verify(auth); err != nil {
+		return "", err
+	}
+
+	hash, err := hashRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = vault.WriteToCubbyhole(r.Namespace, "requests/"+hash, map[string]interface{}{
+		"Type":        r.Type,
+		"Namespace":   r.Namespace,
+		"MountClass":  r.MountClass,
+		"Action":      r.Action,
+		"Path":        r.Path,
+		"MountType":   r.MountType,
+		"Config":      r.Config,
+		"PGPKey":      r.PGPKey,
+		"RequestedBy": r.RequestedBy,
+		"CreatedAt":   r.CreatedAt,
+		"ExpiresAt":   r.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
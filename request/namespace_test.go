@@ -0,0 +1,111 @@
+package request
+
+import "testing"
+
+// Every root-only Request type's hash must be sensitive to Namespace, so
+// that Get/Remove (which look a request up purely by its hash) treat two
+// otherwise-identical requests in different Vault Enterprise namespaces
+// as distinct and never leak approval progress across namespaces.
+//
+// Get/Remove themselves aren't exercised here: both call straight through
+// to the real vault package's cubbyhole functions, which this snapshot
+// doesn't implement (there's no interface seam or fake backend to
+// substitute). hashRequest is the actual mechanism Get/Remove rely on for
+// namespace isolation, so that's what's under test.
+func TestRequestNamespaceHashIsolation(t *testing.T) {
+	cases := []struct {
+		name string
+		a    Request
+		b    Request
+	}{
+		{
+			name: "MountRequest",
+			a: &MountRequest{
+				Type:        "mount",
+				Namespace:   "team-a/",
+				MountClass:  "secret",
+				Action:      "enable",
+				Path:        "secret/",
+				MountType:   "kv",
+				RequestedBy: "alice",
+			},
+			b: &MountRequest{
+				Type:        "mount",
+				Namespace:   "team-b/",
+				MountClass:  "secret",
+				Action:      "enable",
+				Path:        "secret/",
+				MountType:   "kv",
+				RequestedBy: "alice",
+			},
+		},
+		{
+			name: "TokenRequest",
+			a: &TokenRequest{
+				Type:        "token",
+				Namespace:   "team-a/",
+				Policies:    []string{"default"},
+				TTL:         "1h",
+				RequestedBy: "alice",
+			},
+			b: &TokenRequest{
+				Type:        "token",
+				Namespace:   "team-b/",
+				Policies:    []string{"default"},
+				TTL:         "1h",
+				RequestedBy: "alice",
+			},
+		},
+		{
+			name: "SecretWriteRequest",
+			a: &SecretWriteRequest{
+				Type:        "secret_write",
+				Namespace:   "team-a/",
+				Path:        "secret/data/foo",
+				Data:        map[string]interface{}{"value": "bar"},
+				RequestedBy: "alice",
+			},
+			b: &SecretWriteRequest{
+				Type:        "secret_write",
+				Namespace:   "team-b/",
+				Path:        "secret/data/foo",
+				Data:        map[string]interface{}{"value": "bar"},
+				RequestedBy: "alice",
+			},
+		},
+		{
+			name: "PolicyRequest",
+			a: &PolicyRequest{
+				Type:        "policy",
+				Namespace:   "team-a/",
+				Name:        "read-only",
+				Rules:       `path "secret/*" { capabilities = ["read"] }`,
+				RequestedBy: "alice",
+			},
+			b: &PolicyRequest{
+				Type:        "policy",
+				Namespace:   "team-b/",
+				Name:        "read-only",
+				Rules:       `path "secret/*" { capabilities = ["read"] }`,
+				RequestedBy: "alice",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hashA, err := hashRequest(tc.a)
+			if err != nil {
+				t.Fatalf("failed to hash request a: %v", err)
+			}
+			hashB, err := hashRequest(tc.b)
+			if err != nil {
+				t.Fatalf("failed to hash request b: %v", err)
+			}
+
+			if hashA == hashB {
+				t.Fatalf("requests in different namespaces must not share a hash, got %q for both", hashA)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package request
+
+import (
+	"errors"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// PolicyRequest represents a proposed write of a Vault ACL policy. It is
+// approved the same way a MountRequest is: approvers submit unseal keys
+// until a root token can be generated, and that root token is used once
+// to apply the policy via sys/policy.
+type PolicyRequest struct {
+	Type string
+
+	// Vault Enterprise namespace this request was created in
+	Namespace string
+
+	// name of the policy to create or overwrite
+	Name string
+
+	// HCL or JSON policy document
+	Rules string
+
+	// optional: encrypt the generated root token to this PGP key
+	// (fingerprint or armored public key) instead of using an OTP
+	PGPKey string
+
+	RequestedBy string
+
+	// RFC3339 timestamps; ExpiresAt is enforced by request.Reap
+	CreatedAt string
+	ExpiresAt string
+}
+
+func (r *PolicyRequest) IsRootOnly() bool {
+	return true
+}
+
+func (r *PolicyRequest) Verify(auth vault.AuthInfo) error {
+	if r.Name == "" {
+		return errors.New("Policy name cannot be empty")
+	}
+	if r.Rules == "" {
+		return errors.New("Policy request must contain rules")
+	}
+	return nil
+}
+
+// Approve appends an unseal key share to the request's progress, and
+// once enough shares have been collected, generates a root token and
+// applies the policy on behalf of the approvers.
+func (r *PolicyRequest) Approve(hash, unseal string) error {
+	return completeApproval(r.Namespace, hash, unseal, r.PGPKey, r.RequestedBy, r.Type,
+		map[string]interface{}{
+			"Type":        r.Type,
+			"Namespace":   r.Namespace,
+			"Name":        r.Name,
+			"Rules":       r.Rules,
+			"PGPKey":      r.PGPKey,
+			"RequestedBy": r.RequestedBy,
+			"CreatedAt":   r.CreatedAt,
+			"ExpiresAt":   r.ExpiresAt,
+		},
+		func(token string) error {
+			return vault.ApplyPolicy(token, r.Name, r.Rules)
+		})
+}
+
+func (r *PolicyRequest) Reject(auth vault.AuthInfo, hash string) error {
+	return rejectRequest(r.Namespace, hash)
+}
+
+func (r *PolicyRequest) Create(auth vault.AuthInfo, raw map[string]interface{}) (string, error) {
+	if err := mapstructure.Decode(raw, r); err != nil {
+		return "", err
+	}
+	r.Type = "policy"
+	r.RequestedBy = auth.DisplayName
+	// Namespace is part of the request's security context, not
+	// client-suppliable data: it always comes from the requester's own
+	// authenticated auth info, never from raw, even if raw sets one.
+	r.Namespace = auth.Namespace
+	r.CreatedAt, r.ExpiresAt = newExpiry()
+
+	if err := r.Verify(auth); err != nil {
+		return "", err
+	}
+
+	hash, err := hashRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = vault.WriteToCubbyhole(r.Namespace, "requests/"+hash, map[string]interface{}{
+		"Type":        r.Type,
+		"Namespace":   r.Namespace,
+		"Name":        r.Name,
+		"Rules":       r.Rules,
+		"PGPKey":      r.PGPKey,
+		"RequestedBy": r.RequestedBy,
+		"CreatedAt":   r.CreatedAt,
+		"ExpiresAt":   r.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
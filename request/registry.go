@@ -0,0 +1,38 @@
+package request
+
+import "strings"
+
+// Factory returns a new, empty instance of a registered request type.
+// The returned value is always a pointer, so that mapstructure.Decode
+// and hashstructure.Hash can operate on it in place.
+type Factory func() Request
+
+// registry maps a lowercased request "Type" string to the factory that
+// builds it. Packages that want to add a new approval workflow call
+// Register from an init() rather than editing Add/Get/Remove directly.
+var registry = map[string]Factory{}
+
+// Register adds a request type to the registry. It panics on a
+// duplicate name, since that indicates two packages (or two init
+// functions) are fighting over the same request type.
+func Register(name string, factory Factory) {
+	name = strings.ToLower(name)
+	if _, ok := registry[name]; ok {
+		panic("request: Register called twice for type " + name)
+	}
+	registry[name] = factory
+}
+
+// lookup returns the factory for a given type, or false if none is
+// registered.
+func lookup(t string) (Factory, bool) {
+	factory, ok := registry[strings.ToLower(t)]
+	return factory, ok
+}
+
+func init() {
+	Register("policy", func() Request { return &PolicyRequest{} })
+	Register("mount", func() Request { return &MountRequest{} })
+	Register("token", func() Request { return &TokenRequest{} })
+	Register("secret_write", func() Request { return &SecretWriteRequest{} })
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/caiyeon/goldfish/audit"
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/gorilla/securecookie"
 	"github.com/hashicorp/go-uuid"
@@ -33,20 +35,38 @@ func Add(auth vault.AuthInfo, raw map[string]interface{}) (string, error) {
 		return "", errors.New("Type field is empty")
 	}
 
-	switch strings.ToLower(t) {
-	case "policy":
-		var req PolicyRequest
-		return req.Create(auth, raw)
-
-	default:
+	factory, ok := lookup(t)
+	if !ok {
 		return "", errors.New("Unsupported request type")
 	}
+
+	hash, err := factory().Create(auth, raw)
+	if err != nil {
+		return "", err
+	}
+
+	// audit the request as it was actually stored, not as the caller sent
+	// it: Create fills in defaults (e.g. Namespace) that raw may not have
+	body := raw
+	if resp, rerr := vault.ReadFromCubbyhole(auth.Namespace, "requests/"+hash); rerr == nil && resp != nil {
+		body = resp.Data
+	}
+
+	audit.Record(audit.Event{
+		Actor:       auth.DisplayName,
+		Accessor:    auth.Accessor,
+		RequestType: strings.ToLower(t),
+		Hash:        hash,
+		Decision:    audit.DecisionCreated,
+		Body:        audit.Redact(body),
+	})
+	return hash, nil
 }
 
 // fetches a request if it exists, and if user has authentication
 func Get(auth vault.AuthInfo, hash string) (Request, error) {
 	// fetch request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	resp, err := vault.ReadFromCubbyhole(auth.Namespace, "requests/"+hash)
 	if err != nil {
 		return nil, err
 	}
@@ -63,33 +83,32 @@ func Get(auth vault.AuthInfo, hash string) (Request, error) {
 		return nil, errors.New("Invalid request type")
 	}
 
-	switch strings.ToLower(t) {
-	case "policy":
-		// decode secret into policy request
-		var req PolicyRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
-			return nil, err
-		}
-		// verify hash
-		hash_uint64, err := hashstructure.Hash(req, nil)
-		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
-			return nil, errors.New("Hashes do not match")
-		}
-		// verify policy request is still valid
-		if err := req.Verify(auth); err != nil {
-			return nil, err
-		}
-		return &req, nil
-
-	default:
+	factory, ok := lookup(t)
+	if !ok {
 		return nil, errors.New("Invalid request type: " + t)
 	}
+
+	// decode secret into the registered request type
+	req := factory()
+	if err := mapstructure.Decode(resp.Data, req); err != nil {
+		return nil, err
+	}
+	// verify hash
+	reqHash, err := hashRequest(req)
+	if err != nil || reqHash != hash {
+		return nil, errors.New("Hashes do not match")
+	}
+	// verify request is still valid
+	if err := req.Verify(auth); err != nil {
+		return nil, err
+	}
+	return req, nil
 }
 
 // delete request, if user is authorized to read resource
 func Remove(auth vault.AuthInfo, hash string) error {
 	// fetch request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	resp, err := vault.ReadFromCubbyhole(auth.Namespace, "requests/"+hash)
 	if err != nil {
 		return err
 	}
@@ -107,54 +126,105 @@ func Remove(auth vault.AuthInfo, hash string) error {
 	}
 
 	// verify user can access resource
-	switch strings.ToLower(t) {
-	case "policy":
-		// decode secret into policy request
-		var req PolicyRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
-			return err
-		}
-		// verify hash
-		hash_uint64, err := hashstructure.Hash(req, nil)
-		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
-			return errors.New("Hashes do not match")
-		}
-		// verify policy request is still valid
-		return req.Reject(auth, hash)
-
-	default:
+	factory, ok := lookup(t)
+	if !ok {
 		return errors.New("Invalid request type: " + t)
 	}
+
+	// decode secret into the registered request type
+	req := factory()
+	if err := mapstructure.Decode(resp.Data, req); err != nil {
+		return err
+	}
+	// verify hash
+	reqHash, err := hashRequest(req)
+	if err != nil || reqHash != hash {
+		return errors.New("Hashes do not match")
+	}
+
+	if err := req.Reject(auth, hash); err != nil {
+		return err
+	}
+
+	audit.Record(audit.Event{
+		Actor:       auth.DisplayName,
+		Accessor:    auth.Accessor,
+		RequestType: strings.ToLower(t),
+		Hash:        hash,
+		Decision:    audit.DecisionRejected,
+		Body:        audit.Redact(resp.Data),
+	})
+	return nil
+}
+
+// hashRequest computes the hex-encoded hash of a request used as its
+// change ID. Two requests with identical field values, including
+// Namespace, always produce the same hash; any difference (namespace
+// included) produces a different one.
+func hashRequest(req Request) (string, error) {
+	hash_uint64, err := hashstructure.Hash(req, nil)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(hash_uint64, 16), nil
 }
 
 func IsRootOnly(req Request) bool {
 	return req.IsRootOnly()
 }
 
+// errInsufficientShares is returned by generateRootToken when the
+// unseal shares collected so far aren't enough to reconstruct a root
+// token yet. Callers should treat it as "no progress to report", not as
+// a failure: more approvers still need to submit their share.
+var errInsufficientShares = errors.New("Could not generate root token. Was vault re-keyed just now?")
+
 // attempts to generate a root token via unseal keys
 // will return error if another key generation process is underway
-func generateRootToken(unsealKeys []string) (string, error) {
-	otp := base64.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(16))
-	status, err := vault.GenerateRootInit(otp)
+//
+// if pgpKey is non-empty, the root token is returned encrypted to that
+// PGP key (fingerprint of a key already known to vault, or an armored
+// public key) instead of XOR'd with a generated OTP, so the approver can
+// decrypt it offline with their own private key.
+func generateRootToken(namespace string, unsealKeys []string, pgpKey string) (string, error) {
+	otp := ""
+	if pgpKey == "" {
+		otp = base64.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(16))
+	}
+	status, err := vault.GenerateRootInit(namespace, otp, pgpKey)
 	if err != nil {
 		return "", err
 	}
 
 	if status.EncodedRootToken == "" {
 		for _, s := range unsealKeys {
-			status, err = vault.GenerateRootUpdate(s, status.Nonce)
-			// an error likely means one of the unseals was not valid
+			next, err := vault.GenerateRootUpdate(namespace, s, status.Nonce)
 			if err != nil {
-				if err2 := vault.GenerateRootCancel(); err2 != nil {
-					return "", errors.New("Could not generate root token: " +
-						err.Error() + ", " + err2.Error())
+				// a transient error (network blip, 5xx) doesn't mean the
+				// attempt is doomed; only abandon it on an unrecoverable
+				// error, e.g. an unseal key that Vault has flatly rejected
+				rerr := classifyVaultError(err)
+				if !rerr.Recoverable() {
+					if err2 := vault.GenerateRootCancel(namespace); err2 != nil {
+						return "", errors.New("Could not generate root token: " +
+							rerr.Error() + ", " + err2.Error())
+					}
+					return "", rerr
 				}
+				continue
 			}
+			status = next
 		}
 	}
 
 	if status.EncodedRootToken == "" {
-		return "", errors.New("Could not generate root token. Was vault re-keyed just now?")
+		return "", errInsufficientShares
+	}
+
+	// pgp-encrypted tokens come back ready to hand to the approver; there
+	// is no OTP to XOR against
+	if pgpKey != "" {
+		return status.EncodedRootToken, nil
 	}
 
 	tokenBytes, err := xor.XORBase64(status.EncodedRootToken, otp)
@@ -171,14 +241,15 @@ func generateRootToken(unsealKeys []string) (string, error) {
 }
 
 // writes the provided unseal in and returns a slice of all unseals in hash
-func appendUnseal(hash, unseal string) ([]string, error) {
+func appendUnseal(namespace, hash, unseal string) ([]string, error) {
 	// read current request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	resp, err := vault.ReadFromCubbyhole(namespace, "unseal_wrapping_tokens/"+hash)
 	if err != nil {
 		return nil, err
 	}
 
 	var wrappingTokens []string
+	firstWrappedAt := time.Now().UTC()
 
 	// if there are already unseals, read them and append
 	if resp != nil {
@@ -190,39 +261,132 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 			return nil, errors.New("Could not find key 'wrapping_tokens' in cubbyhole")
 		}
 		wrappingTokens = append(wrappingTokens, strings.Split(raw, ";")...)
+
+		if temp, ok := resp.Data["first_wrapped_at"].(string); ok {
+			if parsed, err := time.Parse(time.RFC3339, temp); err == nil {
+				firstWrappedAt = parsed
+			}
+		}
+		if time.Since(firstWrappedAt) > wrappingTokenTTL-nearExpiryWindow {
+			resetWrappingBundle(namespace, hash)
+			return nil, errNearExpiry
+		}
 	}
 
 	// wrap the unseal token
-	newWrappingToken, err := vault.WrapData("60m", map[string]interface{}{
+	newWrappingToken, err := vault.WrapData(namespace, "60m", map[string]interface{}{
 		"unseal_token": unseal,
 	})
 	if err != nil {
-		return nil, err
+		rerr := classifyVaultError(err)
+		if !rerr.Recoverable() {
+			resetWrappingBundle(namespace, hash)
+		}
+		return nil, rerr
 	}
 
 	// add the new unseal key in
 	wrappingTokens = append(wrappingTokens, newWrappingToken)
 
 	// write the unseals back to the cubbyhole
-	_, err = vault.WriteToCubbyhole("unseal_wrapping_tokens/"+hash,
+	_, err = vault.WriteToCubbyhole(namespace, "unseal_wrapping_tokens/"+hash,
 		map[string]interface{}{
-			"wrapping_tokens": strings.Trim(strings.Join(strings.Fields(fmt.Sprint(wrappingTokens)), ";"), "[]"),
+			"wrapping_tokens":  strings.Trim(strings.Join(strings.Fields(fmt.Sprint(wrappingTokens)), ";"), "[]"),
+			"first_wrapped_at": firstWrappedAt.Format(time.RFC3339),
 		},
 	)
 	return wrappingTokens, err
 }
 
-func unwrapUnseals(wrappingTokens []string) (unseals []string, err error) {
+// resetWrappingBundle deletes the unseal_wrapping_tokens/<hash> entry so a
+// bundle that has hit an unrecoverable error doesn't keep being retried
+// against wrapping tokens that are already gone.
+func resetWrappingBundle(namespace, hash string) {
+	vault.DeleteFromCubbyhole(namespace, "unseal_wrapping_tokens/"+hash)
+}
+
+// completeApproval implements the Approve flow shared by every root-only
+// Request type: collect the submitted unseal share, unwrap the bundle
+// once enough shares have been gathered, generate a root token, and hand
+// it to op to perform the type's privileged write. body is the request's
+// own stored map, used to audit exactly what was approved.
+//
+// A nil error with no audit record means not enough shares have been
+// collected yet; progress has been saved for the next approver.
+func completeApproval(namespace, hash, unseal, pgpKey, actor, reqType string, body map[string]interface{}, op func(token string) error) error {
+	wrappingTokens, err := appendUnseal(namespace, hash, unseal)
+	if err != nil {
+		return err
+	}
+
+	unseals, err := unwrapUnseals(namespace, hash, wrappingTokens)
+	if err != nil {
+		return err
+	}
+
+	token, err := generateRootToken(namespace, unseals, pgpKey)
+	if err != nil {
+		if err == errInsufficientShares {
+			// not enough shares yet; progress has been saved
+			return nil
+		}
+		// a genuine failure (possibly unrecoverable, in which case Vault's
+		// root-generation attempt has already been cancelled); surface it
+		// rather than silently reporting success
+		return err
+	}
+
+	if err := op(token); err != nil {
+		return err
+	}
+
+	vault.DeleteFromCubbyhole(namespace, "requests/"+hash)
+	vault.DeleteFromCubbyhole(namespace, "unseal_wrapping_tokens/"+hash)
+
+	// Approve has no vault.AuthInfo of its own (it is driven purely by
+	// unseal key shares); actor (RequestedBy) is the best identity we can audit.
+	audit.Record(audit.Event{
+		Actor:       actor,
+		RequestType: reqType,
+		Hash:        hash,
+		Decision:    audit.DecisionApproved,
+		Body:        audit.Redact(body),
+	})
+	return nil
+}
+
+// rejectRequest deletes a request and any unseal-key progress collected
+// against it from the cubbyhole. Shared by every Request implementation's
+// Reject method.
+func rejectRequest(namespace, hash string) error {
+	if err := vault.DeleteFromCubbyhole(namespace, "requests/"+hash); err != nil {
+		return err
+	}
+	return vault.DeleteFromCubbyhole(namespace, "unseal_wrapping_tokens/"+hash)
+}
+
+func unwrapUnseals(namespace, hash string, wrappingTokens []string) (unseals []string, err error) {
 	for _, wrappingToken := range wrappingTokens {
-		data, err := vault.UnwrapData(wrappingToken)
-		if err != nil {
-			return nil, err
+		data, uerr := vault.UnwrapData(namespace, wrappingToken)
+		if uerr != nil {
+			rerr := classifyVaultError(uerr)
+			if !rerr.Recoverable() {
+				resetWrappingBundle(namespace, hash)
+			}
+			return nil, rerr
+		}
+		if data == nil {
+			resetWrappingBundle(namespace, hash)
+			return nil, NewRequestError(
+				errors.New("Unwrap returned no secret. Progress reset."), false)
 		}
-		if unseal, ok := data["unseal_token"]; ok {
-			unseals = append(unseals, unseal.(string))
-		} else {
-			return nil, errors.New("One of the wrapping tokens timed out. Progress reset.")
+		unseal, ok := data["unseal_token"]
+		if !ok {
+			resetWrappingBundle(namespace, hash)
+			return nil, NewRequestError(
+				errors.New("One of the wrapping tokens timed out. Progress reset."), false)
 		}
+		unseals = append(unseals, unseal.(string))
 	}
 	return
 }
@@ -0,0 +1,109 @@
+package request
+
+import (
+	"errors"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// SecretWriteRequest represents a proposed write (or rotation) of a KV
+// secret. It is approved the same way a PolicyRequest is: approvers
+// submit unseal keys until a root token can be generated, and that root
+// token is used once to perform the write.
+type SecretWriteRequest struct {
+	Type string
+
+	// Vault Enterprise namespace this request was created in
+	Namespace string
+
+	// full path of the secret to write, e.g. "secret/data/foo"
+	Path string
+
+	Data map[string]interface{}
+
+	// optional: encrypt the generated root token to this PGP key
+	// (fingerprint or armored public key) instead of using an OTP
+	PGPKey string
+
+	RequestedBy string
+
+	// RFC3339 timestamps; ExpiresAt is enforced by request.Reap
+	CreatedAt string
+	ExpiresAt string
+}
+
+func (r *SecretWriteRequest) IsRootOnly() bool {
+	return true
+}
+
+func (r *SecretWriteRequest) Verify(auth vault.AuthInfo) error {
+	if r.Path == "" {
+		return errors.New("Secret path cannot be empty")
+	}
+	if len(r.Data) == 0 {
+		return errors.New("Secret write request must contain data")
+	}
+	return nil
+}
+
+// Approve appends an unseal key share to the request's progress, and
+// once enough shares have been collected, generates a root token and
+// writes the secret on behalf of the approvers.
+func (r *SecretWriteRequest) Approve(hash, unseal string) error {
+	return completeApproval(r.Namespace, hash, unseal, r.PGPKey, r.RequestedBy, r.Type,
+		map[string]interface{}{
+			"Type":        r.Type,
+			"Namespace":   r.Namespace,
+			"Path":        r.Path,
+			"Data":        r.Data,
+			"PGPKey":      r.PGPKey,
+			"RequestedBy": r.RequestedBy,
+			"CreatedAt":   r.CreatedAt,
+			"ExpiresAt":   r.ExpiresAt,
+		},
+		func(token string) error {
+			return vault.WriteSecret(token, r.Path, r.Data)
+		})
+}
+
+func (r *SecretWriteRequest) Reject(auth vault.AuthInfo, hash string) error {
+	return rejectRequest(r.Namespace, hash)
+}
+
+func (r *SecretWriteRequest) Create(auth vault.AuthInfo, raw map[string]interface{}) (string, error) {
+	if err := mapstructure.Decode(raw, r); err != nil {
+		return "", err
+	}
+	r.Type = "secret_write"
+	r.RequestedBy = auth.DisplayName
+	// Namespace is part of the request's security context, not
+	// client-suppliable data: it always comes from the requester's own
+	// authenticated auth info, never from raw, even if raw sets one.
+	r.Namespace = auth.Namespace
+	r.CreatedAt, r.ExpiresAt = newExpiry()
+
+	if err := r.Verify(auth); err != nil {
+		return "", err
+	}
+
+	hash, err := hashRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = vault.WriteToCubbyhole(r.Namespace, "requests/"+hash, map[string]interface{}{
+		"Type":        r.Type,
+		"Namespace":   r.Namespace,
+		"Path":        r.Path,
+		"Data":        r.Data,
+		"PGPKey":      r.PGPKey,
+		"RequestedBy": r.RequestedBy,
+		"CreatedAt":   r.CreatedAt,
+		"ExpiresAt":   r.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
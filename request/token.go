@@ -0,0 +1,113 @@
+package request
+
+import (
+	"errors"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// TokenRequest represents a proposed token creation: a set of policies
+// and a TTL that a requester wants minted, subject to approval. Like
+// PolicyRequest and MountRequest, it is approved by collecting unseal
+// key shares until a root token can be generated.
+type TokenRequest struct {
+	Type string
+
+	// Vault Enterprise namespace this request was created in
+	Namespace string
+
+	Policies []string
+	TTL      string
+
+	// if set, the created token is itself an orphan
+	NoParent bool
+
+	// optional: encrypt the generated root token to this PGP key
+	// (fingerprint or armored public key) instead of using an OTP
+	PGPKey string
+
+	RequestedBy string
+
+	// RFC3339 timestamps; ExpiresAt is enforced by request.Reap
+	CreatedAt string
+	ExpiresAt string
+}
+
+func (r *TokenRequest) IsRootOnly() bool {
+	return true
+}
+
+func (r *TokenRequest) Verify(auth vault.AuthInfo) error {
+	if len(r.Policies) == 0 {
+		return errors.New("Token request must specify at least one policy")
+	}
+	if r.TTL == "" {
+		return errors.New("Token request must specify a TTL")
+	}
+	return nil
+}
+
+// Approve appends an unseal key share to the request's progress, and
+// once enough shares have been collected, generates a root token and
+// creates the requested token on behalf of the approvers.
+func (r *TokenRequest) Approve(hash, unseal string) error {
+	return completeApproval(r.Namespace, hash, unseal, r.PGPKey, r.RequestedBy, r.Type,
+		map[string]interface{}{
+			"Type":        r.Type,
+			"Namespace":   r.Namespace,
+			"Policies":    r.Policies,
+			"TTL":         r.TTL,
+			"NoParent":    r.NoParent,
+			"PGPKey":      r.PGPKey,
+			"RequestedBy": r.RequestedBy,
+			"CreatedAt":   r.CreatedAt,
+			"ExpiresAt":   r.ExpiresAt,
+		},
+		func(token string) error {
+			_, err := vault.CreateToken(token, r.Policies, r.TTL, r.NoParent)
+			return err
+		})
+}
+
+func (r *TokenRequest) Reject(auth vault.AuthInfo, hash string) error {
+	return rejectRequest(r.Namespace, hash)
+}
+
+func (r *TokenRequest) Create(auth vault.AuthInfo, raw map[string]interface{}) (string, error) {
+	if err := mapstructure.Decode(raw, r); err != nil {
+		return "", err
+	}
+	r.Type = "token"
+	r.RequestedBy = auth.DisplayName
+	// Namespace is part of the request's security context, not
+	// client-suppliable data: it always comes from the requester's own
+	// authenticated auth info, never from raw, even if raw sets one.
+	r.Namespace = auth.Namespace
+	r.CreatedAt, r.ExpiresAt = newExpiry()
+
+	if err := r.Verify(auth); err != nil {
+		return "", err
+	}
+
+	hash, err := hashRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = vault.WriteToCubbyhole(r.Namespace, "requests/"+hash, map[string]interface{}{
+		"Type":        r.Type,
+		"Namespace":   r.Namespace,
+		"Policies":    r.Policies,
+		"TTL":         r.TTL,
+		"NoParent":    r.NoParent,
+		"PGPKey":      r.PGPKey,
+		"RequestedBy": r.RequestedBy,
+		"CreatedAt":   r.CreatedAt,
+		"ExpiresAt":   r.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}